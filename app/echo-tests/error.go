@@ -2,15 +2,120 @@ package main
 
 import (
 	"fmt"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 )
 
-func (r ServiceError) Error() string {
-	return fmt.Sprintf("status %d: err %v :msg %v", r.Code, r.Err, r.Message)
+// debugErrors controls whether HTTPErrorHandler includes stack frames in
+// the JSON envelope it renders. It is meant for local debugging only.
+var debugErrors = os.Getenv("SANDBOX_DEBUG_ERRORS") != ""
+
+// stackTracer helps to display the stack trace when errors happen.
+type stackTracer interface {
+	StackTrace() errors.StackTrace
 }
 
-// ServiceError handles custom errors
+// ServiceError is the error type returned by every handler and DB helper
+// in this service. It carries enough context (an HTTP status, a stable
+// ErrorCode, the originating error, and the request id that Echo's
+// RequestID middleware attached) for HTTPErrorHandler to render a
+// consistent JSON envelope, whether the failure originated in the HTTP
+// layer or in a DB helper underneath it.
 type ServiceError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message,omitempty"`
-	Err     error  `json:"-"`
+	Code      int    `json:"code"`
+	ErrorCode string `json:"error,omitempty"`
+	Message   string `json:"message,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Err       error  `json:"-"`
+}
+
+func (r *ServiceError) Error() string {
+	return fmt.Sprintf("status %d: err %v :msg %v", r.Code, r.Err, r.Message)
+}
+
+// Unwrap exposes the wrapped error so ServiceError composes with
+// errors.Is and errors.As.
+func (r *ServiceError) Unwrap() error {
+	return r.Err
+}
+
+// StackTrace satisfies stackTracer by delegating to the wrapped error, so
+// a ServiceError can be passed straight to anything that knows how to
+// print a pkg/errors stack trace.
+func (r *ServiceError) StackTrace() errors.StackTrace {
+	if st, ok := r.Err.(stackTracer); ok {
+		return st.StackTrace()
+	}
+	return nil
+}
+
+// NewServiceError builds a ServiceError wrapping err with the given HTTP
+// status and stable ErrorCode (e.g. "db.unavailable", "validation.bbox").
+// err is captured with a stack trace if it doesn't already carry one.
+func NewServiceError(code int, errCode string, err error) *ServiceError {
+	return &ServiceError{
+		Code:      code,
+		ErrorCode: errCode,
+		Message:   err.Error(),
+		Err:       withStack(err),
+	}
+}
+
+// Wrap is like NewServiceError but lets the caller pick the message shown
+// to the client while still preserving err in the chain for
+// errors.Is/errors.As.
+func Wrap(err error, code int, message string) *ServiceError {
+	return &ServiceError{
+		Code:    code,
+		Message: message,
+		Err:     withStack(err),
+	}
+}
+
+// withStack attaches a stack trace to err unless it already carries one.
+func withStack(err error) error {
+	if _, ok := err.(stackTracer); ok {
+		return err
+	}
+	return errors.WithStack(err)
+}
+
+// errorEnvelope is the JSON shape every error response shares, whether it
+// originated in an HTTP handler or bubbled up from a DB helper.
+type errorEnvelope struct {
+	Code      int      `json:"code"`
+	ErrorCode string   `json:"error,omitempty"`
+	Message   string   `json:"message,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+	TraceID   string   `json:"trace_id,omitempty"`
+	Stack     []string `json:"stack,omitempty"`
+}
+
+// envelope builds the JSON envelope for r, including stack frames when
+// debugErrors is set.
+func (r *ServiceError) envelope() errorEnvelope {
+	e := errorEnvelope{
+		Code:      r.Code,
+		ErrorCode: r.ErrorCode,
+		Message:   r.Message,
+		RequestID: r.RequestID,
+		TraceID:   r.RequestID,
+	}
+
+	if debugErrors {
+		for _, f := range r.StackTrace() {
+			e.Stack = append(e.Stack, fmt.Sprintf("%+s:%d", f, f))
+		}
+	}
+
+	return e
+}
+
+// respond stamps r with the request id Echo's RequestID middleware
+// attached to c and renders it as the standard error envelope.
+func (r *ServiceError) respond(c echo.Context) error {
+	r.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	return c.JSON(r.Code, r.envelope())
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	geojson "github.com/kpawlik/geojson"
+	"github.com/labstack/echo/v4"
+)
+
+// featureStore gives the /features handlers access to the shared
+// PostGIS-enabled connection pool opened in main.
+type featureStore struct {
+	db *sql.DB
+}
+
+// registerFeatureRoutes wires up the /features endpoints on server, backed
+// by store.
+func registerFeatureRoutes(server *echo.Echo, store *featureStore) {
+	server.GET("/features", store.index)
+	server.POST("/features", store.create)
+	server.GET("/features/:id", store.show)
+}
+
+// index handles GET /features?bbox=minLon,minLat,maxLon,maxLat[&srid=4326],
+// returning every feature whose bounding box intersects the requested
+// envelope.
+func (s *featureStore) index(c echo.Context) error {
+	minLon, minLat, maxLon, maxLat, err := parseBBox(c.QueryParam("bbox"))
+	if err != nil {
+		return NewServiceError(http.StatusBadRequest, "validation.bbox", err).respond(c)
+	}
+
+	srid := 4326
+	if raw := c.QueryParam("srid"); raw != "" {
+		srid, err = strconv.Atoi(raw)
+		if err != nil {
+			return NewServiceError(http.StatusBadRequest, "validation.srid", err).respond(c)
+		}
+		if err := s.checkSRID(srid); err != nil {
+			return NewServiceError(http.StatusBadRequest, "validation.srid", err).respond(c)
+		}
+	}
+
+	rows, err := s.db.Query(
+		`select id, ST_AsGeoJSON(ST_Transform(geom, $5)) from features
+		 where geom && ST_Transform(ST_MakeEnvelope($1, $2, $3, $4, 4326), ST_SRID(geom))`,
+		minLon, minLat, maxLon, maxLat, srid,
+	)
+	if err != nil {
+		return NewServiceError(http.StatusInternalServerError, "db.unavailable", err).respond(c)
+	}
+	defer rows.Close()
+
+	features := make([]*geojson.Feature, 0)
+	for rows.Next() {
+		var id int64
+		var rawGeoJSON string
+		if err := rows.Scan(&id, &rawGeoJSON); err != nil {
+			return NewServiceError(http.StatusInternalServerError, "db.scan", err).respond(c)
+		}
+
+		feature, err := geometryToFeature(id, rawGeoJSON)
+		if err != nil {
+			return Wrap(err, http.StatusInternalServerError, "failed to decode stored geometry").respond(c)
+		}
+		features = append(features, feature)
+	}
+	if err := rows.Err(); err != nil {
+		return NewServiceError(http.StatusInternalServerError, "db.unavailable", err).respond(c)
+	}
+
+	return c.JSON(http.StatusOK, geojson.NewFeatureCollection(features))
+}
+
+// create handles POST /features, inserting every feature of the posted
+// FeatureCollection in a single transaction and returning the assigned
+// ids, so a failure partway through leaves no rows behind.
+func (s *featureStore) create(c echo.Context) error {
+	var fc geojson.FeatureCollection
+	if err := c.Bind(&fc); err != nil {
+		return NewServiceError(http.StatusBadRequest, "validation.geojson", err).respond(c)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return NewServiceError(http.StatusInternalServerError, "db.unavailable", err).respond(c)
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(fc.Features))
+	for _, feature := range fc.Features {
+		geomJSON, err := geojson.Marshal(feature.Geometry)
+		if err != nil {
+			return Wrap(err, http.StatusBadRequest, "failed to encode feature geometry").respond(c)
+		}
+
+		var id int64
+		err = tx.QueryRow(
+			`insert into features (geom) values (ST_GeomFromGeoJSON($1)) returning id`,
+			geomJSON,
+		).Scan(&id)
+		if err != nil {
+			return NewServiceError(http.StatusBadRequest, "validation.geojson", err).respond(c)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServiceError(http.StatusInternalServerError, "db.unavailable", err).respond(c)
+	}
+
+	return c.JSON(http.StatusCreated, ids)
+}
+
+// show handles GET /features/:id, returning a single feature by its
+// primary key.
+func (s *featureStore) show(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return Wrap(err, http.StatusBadRequest, "id must be an integer").respond(c)
+	}
+
+	var rawGeoJSON string
+	err = s.db.QueryRow(`select ST_AsGeoJSON(geom) from features where id = $1`, id).Scan(&rawGeoJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wrap(err, http.StatusNotFound, fmt.Sprintf("no feature with id %d", id)).respond(c)
+	}
+	if err != nil {
+		return NewServiceError(http.StatusInternalServerError, "db.unavailable", err).respond(c)
+	}
+
+	feature, err := geometryToFeature(id, rawGeoJSON)
+	if err != nil {
+		return Wrap(err, http.StatusInternalServerError, "failed to decode stored geometry").respond(c)
+	}
+
+	return c.JSON(http.StatusOK, feature)
+}
+
+// checkSRID validates that srid is known to the PostGIS spatial_ref_sys
+// table before it is used to transform any geometry.
+func (s *featureStore) checkSRID(srid int) error {
+	var exists bool
+	err := s.db.QueryRow(`select exists(select 1 from spatial_ref_sys where srid = $1)`, srid).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("srid %d is not present in spatial_ref_sys", srid)
+	}
+	return nil
+}
+
+// geometryToFeature wraps the GeoJSON geometry produced by ST_AsGeoJSON
+// into a geojson.Feature carrying the row's id.
+func geometryToFeature(id int64, rawGeoJSON string) (*geojson.Feature, error) {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(rawGeoJSON), &decoded); err != nil {
+		return nil, err
+	}
+
+	geom, err := geojson.ParseGeometry(decoded)
+	if err != nil {
+		return nil, err
+	}
+	return geojson.NewFeature(geom, nil, id), nil
+}
+
+// parseBBox parses a "minLon,minLat,maxLon,maxLat" query parameter.
+func parseBBox(raw string) (minLon, minLat, maxLon, maxLat float64, err error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return 0, 0, 0, 0, fmt.Errorf("bbox must have 4 comma-separated values, got %q", raw)
+	}
+
+	values := make([]float64, 4)
+	for i, part := range parts {
+		values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0, 0, 0, 0, fmt.Errorf("bbox value %q is not a number", part)
+		}
+	}
+
+	return values[0], values[1], values[2], values[3], nil
+}
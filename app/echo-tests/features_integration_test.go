@@ -0,0 +1,54 @@
+//go:build integration
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+
+	"github.com/brownie2002/go-sandbox/pkg/dbtest"
+)
+
+func TestFeaturesCreateAndIndex(t *testing.T) {
+	dbtest.WithPostgres(t, dbtest.SpatialImage, func(dsn string) {
+		conn, err := sqlx.Open("postgres", dsn)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.Exec(`create extension if not exists postgis`); err != nil {
+			t.Fatalf("failed to enable postgis: %v", err)
+		}
+		if _, err := conn.Exec(`create table features (id serial primary key, geom geometry(Point, 4326))`); err != nil {
+			t.Fatalf("failed to create features table: %v", err)
+		}
+
+		server := echo.New()
+		store := &featureStore{db: conn.DB}
+		registerFeatureRoutes(server, store)
+
+		body := `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[2.5,48.8]},"properties":null}]}`
+		req := httptest.NewRequest(http.MethodPost, "/features", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201 creating a feature, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		listReq := httptest.NewRequest(http.MethodGet, "/features?bbox=2,48,3,49", nil)
+		listRec := httptest.NewRecorder()
+		server.ServeHTTP(listRec, listReq)
+
+		if listRec.Code != http.StatusOK {
+			t.Fatalf("expected 200 listing features, got %d: %s", listRec.Code, listRec.Body.String())
+		}
+	})
+}
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+
+	"github.com/brownie2002/go-sandbox/pkg/db"
 )
 
 func main() {
@@ -20,17 +24,41 @@ func main() {
 	server.Debug = false
 	server.HideBanner = true
 	server.HTTPErrorHandler = func(err error, c echo.Context) {
-		// Print to stdout
-		fmt.Println("Message from HTTPErrorHandler", c.Path(), c.QueryParams(), err)
+		svcErr, ok := err.(*ServiceError)
+		if !ok {
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				svcErr = Wrap(err, httpErr.Code, fmt.Sprintf("%v", httpErr.Message))
+			} else {
+				svcErr = Wrap(err, http.StatusInternalServerError, err.Error())
+			}
+		}
+
+		if c.Response().Committed {
+			return
+		}
 
-		// Call the default handler to return the HTTP response
-		server.DefaultHTTPErrorHandler(err, c)
+		if respErr := svcErr.respond(c); respErr != nil {
+			fmt.Println("Message from HTTPErrorHandler", c.Path(), c.QueryParams(), respErr)
+		}
 	}
 
+	// The /features endpoints need a PostGIS-enabled Postgres database,
+	// shared through the pool opened by pkg/db.
+	conn, err := db.Connect(db.Config{
+		Type:        "postgres",
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Connect always hands back the concrete *sqlx.DB behind the db.DB
+	// interface, so the raw *sql.DB can be reused by the feature handlers.
+	registerFeatureRoutes(server, &featureStore{db: conn.(*sqlx.DB).DB})
+
 	server.GET("/users", func(c echo.Context) error {
 		users, err := dbGetUsers()
 		if err != nil {
-			return c.JSON(err.Code, err)
+			return err.respond(c)
 		}
 
 		return c.JSON(http.StatusOK, users)
@@ -39,7 +67,7 @@ func main() {
 	server.GET("/posts", func(c echo.Context) error {
 		users, err := dbPostUsers()
 		if err != nil {
-			return echo.NewHTTPError(err.Code, err)
+			return err.respond(c)
 		}
 
 		return c.JSON(http.StatusOK, users)
@@ -49,20 +77,9 @@ func main() {
 }
 
 func dbGetUsers() ([]string, *ServiceError) {
-
-	err := &ServiceError{
-		Code:    http.StatusBadRequest,
-		Message: "Error for get endpoint..",
-		Err:     errors.New("unavailable"),
-	}
-
-	return nil, err
+	return nil, NewServiceError(http.StatusBadRequest, "db.unavailable", errors.New("unavailable"))
 }
 
 func dbPostUsers() ([]string, *ServiceError) {
-
-	return nil, &ServiceError{http.StatusTeapot,
-		"Error for post endpoint.",
-		errors.New("Another error."),
-	}
+	return nil, Wrap(errors.New("Another error."), http.StatusTeapot, "Error for post endpoint.")
 }
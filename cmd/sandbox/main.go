@@ -0,0 +1,81 @@
+// Command sandbox is the entry point for operational tasks (currently just
+// schema migrations) shared across the example services in this repo.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/brownie2002/go-sandbox/pkg/db"
+	"github.com/brownie2002/go-sandbox/pkg/migrate"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "migrate" {
+		fmt.Println("usage: sandbox migrate up|down [n]|version")
+		os.Exit(1)
+	}
+
+	if err := runMigrate(os.Args[2:]); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runMigrate(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: sandbox migrate up|down [n]|version")
+	}
+
+	cfg := db.Config{
+		Type:        envOr("SANDBOX_DB_TYPE", "postgres"),
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+		DSN:         os.Getenv("SANDBOX_DB_DSN"),
+	}
+
+	conn, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Connect always hands back the concrete *sqlx.DB behind the db.DB
+	// interface, so migrate.New can use it directly for Rebind support.
+	m, err := migrate.New(conn.(*sqlx.DB), migrate.Dialect(cfg.Type), nil)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up()
+	case "down":
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid step count %q: %w", args[1], err)
+			}
+			return m.Steps(-n)
+		}
+		return m.Down()
+	case "version":
+		version, dirty, err := m.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version %d (dirty: %v)\n", version, dirty)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q", args[0])
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Config describes how to reach a database and how to size the pool used
+// to talk to it, so a program can take its connection settings from here
+// instead of hardcoding a connection string.
+type Config struct {
+	// Type selects the driver to dispatch on: "sqlite3" or "postgres".
+	Type string
+
+	// DSN is the driver-specific connection string (a file path for
+	// sqlite3, a key=value string for postgres). It is ignored when Type
+	// is "postgres" and DatabaseURL is set.
+	DSN string
+
+	// DatabaseURL is a postgres URL-style connection string, e.g.
+	// "postgres://user:pw@host:port/db?sslmode=disable". When Type is
+	// "postgres" it takes precedence over DSN; it is ignored otherwise.
+	DatabaseURL string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// resolveDSN turns a Config into the DSN that should be passed to the
+// driver, parsing DatabaseURL with pq.ParseURL when one was provided.
+// DatabaseURL is postgres-only (pq.ParseURL rejects any other scheme), so
+// it is ignored in favor of DSN for every other Type.
+func (c Config) resolveDSN() (string, error) {
+	if c.DatabaseURL == "" || c.Type != "postgres" {
+		return c.DSN, nil
+	}
+
+	dsn, err := pq.ParseURL(c.DatabaseURL)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse DATABASE_URL")
+	}
+
+	return dsn, nil
+}
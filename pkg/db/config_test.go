@@ -0,0 +1,39 @@
+package db
+
+import "testing"
+
+func TestResolveDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "postgres DatabaseURL is parsed",
+			cfg:  Config{Type: "postgres", DatabaseURL: "postgres://user:pw@localhost:5432/db?sslmode=disable"},
+			want: "dbname='db' host='localhost' password='pw' port='5432' sslmode='disable' user='user'",
+		},
+		{
+			name: "sqlite3 ignores DatabaseURL and uses DSN",
+			cfg:  Config{Type: "sqlite3", DatabaseURL: "postgres://user:pw@localhost:5432/db", DSN: "file:test.db"},
+			want: "file:test.db",
+		},
+		{
+			name: "no DatabaseURL uses DSN regardless of Type",
+			cfg:  Config{Type: "postgres", DSN: "host=localhost dbname=db"},
+			want: "host=localhost dbname=db",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.resolveDSN()
+			if err != nil {
+				t.Fatalf("resolveDSN() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("resolveDSN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Package db exposes a pluggable SQL driver abstraction on top of sqlx,
+// so callers stop hand-rolling sql.Open/rows.Scan loops and instead share
+// a single, properly pooled connection.
+package db
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"           // postgres driver
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver
+	"github.com/pkg/errors"
+)
+
+// DB is the set of typed query helpers the rest of the codebase needs.
+// It is satisfied directly by *sqlx.DB.
+type DB interface {
+	Select(dest interface{}, query string, args ...interface{}) error
+	Get(dest interface{}, query string, args ...interface{}) error
+	NamedExec(query string, arg interface{}) (sql.Result, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Ping() error
+	Close() error
+}
+
+// Connect opens a pooled connection for cfg.Type ("sqlite3" or "postgres"),
+// applies the pool settings from cfg and pings the database before
+// returning, so callers find out about a bad connection immediately
+// rather than on the first query.
+func Connect(cfg Config) (DB, error) {
+	dsn, err := cfg.resolveDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sqlx.Open(cfg.Type, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open a %s connection", cfg.Type)
+	}
+
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "failed to ping the %s database", cfg.Type)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/brownie2002/go-sandbox/pkg/db"
+	"github.com/brownie2002/go-sandbox/pkg/dbtest"
+)
+
+// crud mirrors the assertions duplicated across showExamplesWithSqlLite
+// and showExamplesWithPostgres, run here against a real, disposable
+// Postgres container instead of a developer's machine.
+func crud(t *testing.T, conn db.DB) {
+	t.Helper()
+
+	if _, err := conn.Exec(`create table foo (id integer not null primary key, name text)`); err != nil {
+		t.Fatalf("failed to create table foo: %v", err)
+	}
+
+	if _, err := conn.NamedExec(`insert into foo (id, name) values (:id, :name)`, map[string]interface{}{"id": 1, "name": "bar"}); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+
+	var names []string
+	if err := conn.Select(&names, `select name from foo order by id`); err != nil {
+		t.Fatalf("failed to select rows: %v", err)
+	}
+	if len(names) != 1 || names[0] != "bar" {
+		t.Fatalf("expected [bar], got %v", names)
+	}
+
+	var name string
+	if err := conn.Get(&name, `select name from foo where id = $1`, 1); err != nil {
+		t.Fatalf("failed to get row: %v", err)
+	}
+	if name != "bar" {
+		t.Fatalf("expected bar, got %q", name)
+	}
+}
+
+func TestConnectCRUD(t *testing.T) {
+	for _, image := range dbtest.Images {
+		image := image
+		t.Run(image, func(t *testing.T) {
+			dbtest.WithPostgres(t, image, func(dsn string) {
+				conn, err := db.Connect(db.Config{
+					Type:            "postgres",
+					DatabaseURL:     dsn,
+					MaxOpenConns:    5,
+					MaxIdleConns:    5,
+					ConnMaxLifetime: 0,
+				})
+				if err != nil {
+					t.Fatalf("failed to connect: %v", err)
+				}
+				defer conn.Close()
+
+				crud(t, conn)
+			})
+		})
+	}
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+// Package dbtest spins up ephemeral Postgres containers for integration
+// tests, mirroring the pattern used by golang-migrate's own Postgres
+// driver tests. It is gated behind the "integration" build tag so
+// `go test ./...` stays fast.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dhui/dktest"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Images are the Postgres server versions the pkg/db and pkg/migrate
+// integration suites are run against.
+var Images = []string{"postgres:12", "postgres:14", "postgres:16"}
+
+// SpatialImage additionally exercises the PostGIS extension needed by the
+// /features endpoints.
+const SpatialImage = "postgis/postgis:14-3.3"
+
+const containerPassword = "password"
+
+var containerOpts = dktest.Options{
+	Env:          map[string]string{"POSTGRES_PASSWORD": containerPassword},
+	PortRequired: true,
+	ReadyFunc:    isReady,
+}
+
+// isReady reports whether the container accepts connections, using a
+// single db.Ping() bounded by a 5 second timeout. dktest calls it
+// repeatedly until it returns true or dktest's ready timeout expires.
+func isReady(ctx context.Context, c dktest.ContainerInfo) bool {
+	host, port, err := c.FirstPort()
+	if err != nil {
+		return false
+	}
+
+	conn, err := sqlx.Open("postgres", dsn(host, port))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return conn.PingContext(ctx) == nil
+}
+
+func dsn(host, port string) string {
+	return fmt.Sprintf("postgres://postgres:%s@%s:%s/postgres?sslmode=disable", containerPassword, host, port)
+}
+
+// WithPostgres starts a disposable container for image, waits for it to
+// become ready, and calls fn with the DSN to reach it. t is failed if the
+// container never becomes ready.
+func WithPostgres(t *testing.T, image string, fn func(dsn string)) {
+	t.Helper()
+
+	dktest.Run(t, image, containerOpts, func(t *testing.T, c dktest.ContainerInfo) {
+		host, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fn(dsn(host, port))
+	})
+}
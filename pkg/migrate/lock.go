@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// lockID is an arbitrary, fixed advisory lock key shared by every Migrator
+// talking to the same Postgres database, so that concurrent migrate
+// invocations serialize instead of racing.
+const lockID = 861729
+
+// lock takes an exclusive, dialect-appropriate lock on the database for the
+// duration of a migration run and returns the dedicated connection the lock
+// was acquired on (so the migration body and the unlock itself can reuse
+// it) along with a function to release the lock. Postgres uses
+// pg_advisory_lock; sqlite has no equivalent concept, so a BEGIN IMMEDIATE
+// transaction is used to obtain a write lock instead. Both are
+// connection-scoped, so everything between lock and unlock must run on the
+// same *sql.Conn rather than going back through the pool.
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, func(), error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to acquire a dedicated connection")
+	}
+
+	switch m.dialect {
+	case Postgres:
+		if _, err := conn.ExecContext(ctx, `select pg_advisory_lock($1)`, lockID); err != nil {
+			conn.Close()
+			return nil, nil, errors.Wrap(err, "failed to acquire advisory lock")
+		}
+		return conn, func() {
+			conn.ExecContext(ctx, `select pg_advisory_unlock($1)`, lockID)
+			conn.Close()
+		}, nil
+
+	case SQLite:
+		if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+			conn.Close()
+			return nil, nil, errors.Wrap(err, "failed to acquire sqlite write lock")
+		}
+		return conn, func() {
+			conn.ExecContext(ctx, `COMMIT`)
+			conn.Close()
+		}, nil
+
+	default:
+		conn.Close()
+		return nil, nil, errors.Errorf("unsupported dialect %q", m.dialect)
+	}
+}
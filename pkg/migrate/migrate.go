@@ -0,0 +1,272 @@
+// Package migrate manages versioned SQL migrations against the SQLite and
+// PostgreSQL backends, as a versioned alternative to the ad-hoc
+// deleteSampleTableIfExists + createSampleTable dance in databaseAccess.go.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+//go:embed migrations/*.sql
+var defaultMigrations embed.FS
+
+// Dialect identifies the SQL dialect a Migrator talks to, so it can pick
+// its locking strategy accordingly. Placeholder syntax is adapted by the
+// *sqlx.DB's Rebind, which goes by the DB's driver name.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite3"
+)
+
+// Migration is one numbered migration step, loaded from a pair of
+// NNN_name.up.sql / NNN_name.down.sql files.
+type Migration struct {
+	Version int64
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+var migrationFilename = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// loadMigrations reads every NNN_name.up.sql/down.sql pair from fsys and
+// returns them sorted by version.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read migrations directory")
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		m := migrationFilename.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration version in %q", entry.Name())
+		}
+
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read migration file %q", entry.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+
+		switch m[3] {
+		case "up":
+			mig.UpSQL = string(contents)
+		case "down":
+			mig.DownSQL = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// querier and execer are the subsets of *sqlx.DB and *sql.Conn that
+// readVersion/apply/revert need, so those methods can run equally against
+// the pool or against the single connection a lock was acquired on.
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Migrator applies migrations against db in order, recording applied
+// versions in a schema_migrations table.
+type Migrator struct {
+	db         *sqlx.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// New builds a Migrator for dialect, loading migrations from fsys. Pass nil
+// to use the package's embedded default migrations.
+func New(db *sqlx.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+	if fsys == nil {
+		sub, err := fs.Sub(defaultMigrations, "migrations")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open embedded migrations")
+		}
+		fsys = sub
+	}
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{db: db, dialect: dialect, migrations: migrations}
+	if err := m.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec(`create table if not exists schema_migrations (version bigint primary key, dirty boolean not null default false)`)
+	return errors.Wrap(err, "failed to create schema_migrations table")
+}
+
+// Version returns the currently applied migration version, whether it was
+// left dirty by a failed migration, and an error if the lookup failed.
+// A version of 0 with dirty false means no migration has been applied yet.
+func (m *Migrator) Version() (version int64, dirty bool, err error) {
+	return m.readVersion(context.Background(), m.db)
+}
+
+// readVersion is the shared implementation behind Version, parameterized
+// over the connection to read through: the pool outside of a lock, or the
+// single *sql.Conn a lock was acquired on while one is held.
+func (m *Migrator) readVersion(ctx context.Context, q querier) (version int64, dirty bool, err error) {
+	row := q.QueryRowContext(ctx, `select version, dirty from schema_migrations order by version desc limit 1`)
+	if err := row.Scan(&version, &dirty); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, errors.Wrap(err, "failed to read schema_migrations")
+	}
+	return version, dirty, nil
+}
+
+// Force sets the recorded version to v without running any migration,
+// clearing the dirty flag. It is the escape hatch to recover after a
+// migration failed halfway and left the database dirty.
+func (m *Migrator) Force(v int64) error {
+	ctx := context.Background()
+
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, `delete from schema_migrations`); err != nil {
+		return errors.Wrap(err, "failed to clear schema_migrations")
+	}
+
+	_, err = conn.ExecContext(ctx, m.db.Rebind(`insert into schema_migrations (version, dirty) values (?, false)`), v)
+	return errors.Wrap(err, "failed to force schema_migrations version")
+}
+
+// Up applies every migration with a version greater than the current one.
+func (m *Migrator) Up() error {
+	return m.Steps(len(m.migrations))
+}
+
+// Down rolls back every applied migration, in reverse order.
+func (m *Migrator) Down() error {
+	return m.Steps(-len(m.migrations))
+}
+
+// Steps applies n pending migrations when n is positive, or rolls back -n
+// applied migrations when n is negative.
+func (m *Migrator) Steps(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, dirty, err := m.readVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return errors.Errorf("database is dirty at version %d, run Force to recover", current)
+	}
+
+	if n > 0 {
+		return m.stepUp(ctx, conn, current, n)
+	}
+	return m.stepDown(ctx, conn, current, -n)
+}
+
+func (m *Migrator) stepUp(ctx context.Context, conn execer, current int64, n int) error {
+	applied := 0
+	for _, mig := range m.migrations {
+		if applied == n {
+			break
+		}
+		if mig.Version <= current {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig.Version, mig.UpSQL); err != nil {
+			return errors.Wrapf(err, "failed to apply migration %d_%s", mig.Version, mig.Name)
+		}
+		applied++
+	}
+	return nil
+}
+
+func (m *Migrator) stepDown(ctx context.Context, conn execer, current int64, n int) error {
+	applied := 0
+	for i := len(m.migrations) - 1; i >= 0 && applied < n; i-- {
+		mig := m.migrations[i]
+		if mig.Version > current {
+			continue
+		}
+		if err := m.revert(ctx, conn, mig.Version, mig.DownSQL); err != nil {
+			return errors.Wrapf(err, "failed to revert migration %d_%s", mig.Version, mig.Name)
+		}
+		applied++
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn execer, version int64, stmt string) error {
+	if _, err := conn.ExecContext(ctx, m.db.Rebind(`insert into schema_migrations (version, dirty) values (?, true)`), version); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, m.db.Rebind(`update schema_migrations set dirty = false where version = ?`), version)
+	return err
+}
+
+func (m *Migrator) revert(ctx context.Context, conn execer, version int64, stmt string) error {
+	if _, err := conn.ExecContext(ctx, m.db.Rebind(`update schema_migrations set dirty = true where version = ?`), version); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, m.db.Rebind(`delete from schema_migrations where version = ?`), version)
+	return err
+}
@@ -0,0 +1,59 @@
+//go:build integration
+
+package migrate_test
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/brownie2002/go-sandbox/pkg/dbtest"
+	"github.com/brownie2002/go-sandbox/pkg/migrate"
+)
+
+func TestUpDownVersion(t *testing.T) {
+	for _, image := range dbtest.Images {
+		image := image
+		t.Run(image, func(t *testing.T) {
+			dbtest.WithPostgres(t, image, func(dsn string) {
+				conn, err := sqlx.Open("postgres", dsn)
+				if err != nil {
+					t.Fatalf("failed to connect: %v", err)
+				}
+				defer conn.Close()
+
+				m, err := migrate.New(conn, migrate.Postgres, nil)
+				if err != nil {
+					t.Fatalf("failed to build migrator: %v", err)
+				}
+
+				if err := m.Up(); err != nil {
+					t.Fatalf("failed to apply migrations: %v", err)
+				}
+
+				version, dirty, err := m.Version()
+				if err != nil {
+					t.Fatalf("failed to read version: %v", err)
+				}
+				if dirty {
+					t.Fatalf("expected a clean migration, got dirty at version %d", version)
+				}
+				if version != 1 {
+					t.Fatalf("expected version 1, got %d", version)
+				}
+
+				if _, err := conn.Exec(`insert into foo (id, name) values (1, 'bar')`); err != nil {
+					t.Fatalf("failed to insert into migrated table: %v", err)
+				}
+
+				if err := m.Down(); err != nil {
+					t.Fatalf("failed to roll back migrations: %v", err)
+				}
+
+				if _, err := conn.Query(`select 1 from foo`); err == nil {
+					t.Fatal("expected table foo to have been dropped by Down")
+				}
+			})
+		})
+	}
+}